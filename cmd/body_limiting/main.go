@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/ivanlemeshev/resilience"
+)
+
+type Handler struct{}
+
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+func (h *Handler) Handler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		fmt.Fprint(w, http.StatusText(http.StatusRequestEntityTooLarge))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "read %d bytes", len(body))
+}
+
+func main() {
+	h := NewHandler()
+
+	// Cap request bodies at 1MB; the shedder/rate-limiter protect CPU,
+	// this protects memory from oversized payloads.
+	http.Handle("/", resilience.LimitBodyMiddleware(1<<20, http.HandlerFunc(h.Handler)))
+
+	log.Fatal(http.ListenAndServe(":8080", nil))
+}
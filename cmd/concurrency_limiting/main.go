@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ConcurrencyLimiter caps the number of requests being served at once using
+// a buffered channel as a semaphore, with an optional bounded FIFO queue
+// for requests that arrive while the limiter is full. It complements
+// LoadShedder: the shedder reacts to observed latency, while the limiter
+// enforces a hard ceiling on concurrency regardless of latency.
+type ConcurrencyLimiter struct {
+	slots chan struct{}
+	queue chan struct{}
+
+	acquireTimeout time.Duration
+
+	inFlight atomic.Int64
+	queued   atomic.Int64
+	rejected atomic.Int64
+	timedOut atomic.Int64
+}
+
+// NewConcurrencyLimiter returns a ConcurrencyLimiter that allows at most
+// maxInFlight requests to be served concurrently, with up to maxQueued
+// additional requests allowed to wait for a free slot. acquireTimeout
+// bounds how long a request waits in the queue before being rejected.
+func NewConcurrencyLimiter(maxInFlight, maxQueued int, acquireTimeout time.Duration) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		slots:          make(chan struct{}, maxInFlight),
+		queue:          make(chan struct{}, maxQueued),
+		acquireTimeout: acquireTimeout,
+	}
+}
+
+// Acquire reserves a slot, waiting in the queue if none is immediately
+// available. It returns false if the queue is full, the acquire timeout
+// elapses, or ctx is canceled before a slot is obtained; in all of these
+// cases no slot is held and the caller must not call release.
+func (cl *ConcurrencyLimiter) Acquire(ctx context.Context) (release func(), ok bool) {
+	select {
+	case cl.slots <- struct{}{}:
+		cl.inFlight.Add(1)
+		return cl.releaseFunc(), true
+	default:
+	}
+
+	select {
+	case cl.queue <- struct{}{}:
+		cl.queued.Add(1)
+		defer func() {
+			<-cl.queue
+			cl.queued.Add(-1)
+		}()
+	default:
+		cl.rejected.Add(1)
+		return nil, false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cl.acquireTimeout)
+	defer cancel()
+
+	select {
+	case cl.slots <- struct{}{}:
+		cl.inFlight.Add(1)
+		return cl.releaseFunc(), true
+	case <-ctx.Done():
+		cl.timedOut.Add(1)
+		return nil, false
+	}
+}
+
+func (cl *ConcurrencyLimiter) releaseFunc() func() {
+	return func() {
+		<-cl.slots
+		cl.inFlight.Add(-1)
+	}
+}
+
+// InFlight returns the number of requests currently holding a slot.
+func (cl *ConcurrencyLimiter) InFlight() int64 {
+	return cl.inFlight.Load()
+}
+
+// Queued returns the number of requests currently waiting for a slot.
+func (cl *ConcurrencyLimiter) Queued() int64 {
+	return cl.queued.Load()
+}
+
+// Rejected returns the number of requests turned away because the queue
+// itself was full.
+func (cl *ConcurrencyLimiter) Rejected() int64 {
+	return cl.rejected.Load()
+}
+
+// TimedOut returns the number of requests that waited in the queue but
+// never acquired a slot, either because the acquire timeout elapsed or
+// because their context was canceled first.
+func (cl *ConcurrencyLimiter) TimedOut() int64 {
+	return cl.timedOut.Load()
+}
+
+// Middleware wraps next with the concurrency limiter, rejecting requests
+// that cannot acquire a slot with a 503 and a Retry-After header.
+func (cl *ConcurrencyLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		release, ok := cl.Acquire(r.Context())
+		if !ok {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, http.StatusText(http.StatusServiceUnavailable))
+			return
+		}
+		defer release()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+type Handler struct{}
+
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+func (h *Handler) Handler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, http.StatusText(http.StatusOK))
+}
+
+func main() {
+	// Serve at most 100 requests concurrently, with up to 1000 more
+	// allowed to wait up to 500ms for a free slot.
+	cl := NewConcurrencyLimiter(100, 1000, 500*time.Millisecond)
+
+	h := NewHandler()
+	http.Handle("/", cl.Middleware(http.HandlerFunc(h.Handler)))
+
+	log.Fatal(http.ListenAndServe(":8080", nil))
+}
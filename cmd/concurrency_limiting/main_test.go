@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	t.Run("requests within capacity are all accepted", func(t *testing.T) {
+		cl := NewConcurrencyLimiter(10, 10, 100*time.Millisecond)
+
+		releases := make([]func(), 0, 10)
+		for i := 0; i < 10; i++ {
+			release, ok := cl.Acquire(context.Background())
+			assert.True(t, ok)
+			releases = append(releases, release)
+		}
+
+		assert.EqualValues(t, 10, cl.InFlight())
+
+		for _, release := range releases {
+			release()
+		}
+
+		assert.EqualValues(t, 0, cl.InFlight())
+	})
+
+	t.Run("requests beyond capacity and queue are rejected", func(t *testing.T) {
+		cl := NewConcurrencyLimiter(1, 0, 100*time.Millisecond)
+
+		release, ok := cl.Acquire(context.Background())
+		assert.True(t, ok)
+
+		_, ok = cl.Acquire(context.Background())
+		assert.False(t, ok)
+		assert.EqualValues(t, 1, cl.Rejected())
+
+		release()
+	})
+
+	t.Run("queued requests time out when no slot frees up", func(t *testing.T) {
+		cl := NewConcurrencyLimiter(1, 1, 20*time.Millisecond)
+
+		release, ok := cl.Acquire(context.Background())
+		assert.True(t, ok)
+
+		_, ok = cl.Acquire(context.Background())
+		assert.False(t, ok)
+		assert.EqualValues(t, 1, cl.TimedOut())
+
+		release()
+	})
+
+	t.Run("a queued request acquires a slot once one frees up", func(t *testing.T) {
+		cl := NewConcurrencyLimiter(1, 1, 200*time.Millisecond)
+
+		release, ok := cl.Acquire(context.Background())
+		assert.True(t, ok)
+
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, ok := cl.Acquire(context.Background())
+			assert.True(t, ok)
+		}()
+
+		time.Sleep(20 * time.Millisecond)
+		release()
+
+		wg.Wait()
+	})
+
+	t.Run("a canceled context releases the queue slot", func(t *testing.T) {
+		cl := NewConcurrencyLimiter(1, 1, time.Second)
+
+		release, ok := cl.Acquire(context.Background())
+		assert.True(t, ok)
+		defer release()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, ok = cl.Acquire(ctx)
+		assert.False(t, ok)
+		assert.EqualValues(t, 0, cl.Queued())
+	})
+}
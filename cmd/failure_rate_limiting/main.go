@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Reservation is a two-phase handle returned by FailureRateLimiter.Allow.
+// The caller performs its operation and then reports the outcome exactly
+// once: Succeeded leaves the failure budget untouched, Failed commits a
+// token against it. This lets the limiter count only failed operations,
+// unlike a plain rate limiter which would also throttle healthy traffic.
+//
+// Allow only checks that a token is available; it does not consume one,
+// since golang.org/x/time/rate's Reservation.Cancel refuses to give back
+// a token once it has been granted (any already-satisfied reservation
+// reports Delay() == 0, and CancelAt is then a no-op). The failure budget
+// is therefore tracked with a plain token bucket instead, which Failed
+// debits directly.
+type Reservation struct {
+	bucket *tokenBucket
+	done   bool
+}
+
+// Succeeded marks the reservation resolved without touching the failure
+// budget, since the operation did not fail.
+func (r *Reservation) Succeeded() {
+	r.done = true
+}
+
+// Failed commits a token against the failure budget for this reservation.
+func (r *Reservation) Failed() {
+	if r.done {
+		return
+	}
+	r.done = true
+	r.bucket.commit()
+}
+
+// tokenBucket is a manually refilled token bucket: tokens accrue at rate
+// per second up to capacity, and are only removed by an explicit commit,
+// never by merely checking availability.
+type tokenBucket struct {
+	mu       sync.Mutex
+	tokens   float64
+	capacity float64
+	rate     float64
+	last     time.Time
+
+	lastUsed time.Time
+}
+
+func newTokenBucket(limit rate.Limit, burst int) *tokenBucket {
+	capacity := float64(burst)
+	return &tokenBucket{
+		tokens:   capacity,
+		capacity: capacity,
+		rate:     float64(limit),
+		last:     time.Now(),
+		lastUsed: time.Now(),
+	}
+}
+
+func (b *tokenBucket) refillLocked(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	b.tokens = math.Min(b.capacity, b.tokens+elapsed*b.rate)
+	b.last = now
+}
+
+// available reports whether a token can currently be committed, without
+// consuming one.
+func (b *tokenBucket) available() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refillLocked(time.Now())
+	return b.tokens >= 1
+}
+
+// commit consumes a single token.
+func (b *tokenBucket) commit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refillLocked(time.Now())
+	b.tokens--
+	if b.tokens < 0 {
+		b.tokens = 0
+	}
+}
+
+// FailureRateLimiter limits how many failures per key are tolerated within
+// a time window, without throttling successful operations at all. It is
+// useful for throttling brute-force login attempts, repeated 5xx
+// responses to a bad client, or webhook retry storms.
+type FailureRateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+
+	limit rate.Limit
+	burst int
+	ttl   time.Duration
+}
+
+// NewFailureRateLimiter returns a FailureRateLimiter that allows at most
+// burst failures, refilling at limit failures per second, per key before
+// rejecting further attempts for that key. Per-key buckets idle for
+// longer than ttl are evicted. The background eviction goroutine stops
+// once ctx is done.
+func NewFailureRateLimiter(ctx context.Context, limit rate.Limit, burst int, ttl time.Duration) *FailureRateLimiter {
+	frl := &FailureRateLimiter{
+		buckets: make(map[string]*tokenBucket),
+		limit:   limit,
+		burst:   burst,
+		ttl:     ttl,
+	}
+
+	go frl.runEvictor(ctx)
+
+	return frl
+}
+
+func (frl *FailureRateLimiter) runEvictor(ctx context.Context) {
+	ticker := time.NewTicker(frl.ttl)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			frl.mu.Lock()
+			now := time.Now()
+			for key, b := range frl.buckets {
+				if now.Sub(b.lastUsed) > frl.ttl {
+					delete(frl.buckets, key)
+				}
+			}
+			frl.mu.Unlock()
+		}
+	}
+}
+
+// Allow checks whether key is currently within its failure budget and
+// returns a Reservation the caller must resolve with Succeeded or Failed
+// once the operation completes.
+func (frl *FailureRateLimiter) Allow(key string) (*Reservation, bool) {
+	frl.mu.Lock()
+	b, ok := frl.buckets[key]
+	if !ok {
+		b = newTokenBucket(frl.limit, frl.burst)
+		frl.buckets[key] = b
+	}
+	b.lastUsed = time.Now()
+	frl.mu.Unlock()
+
+	if !b.available() {
+		return nil, false
+	}
+
+	return &Reservation{bucket: b}, true
+}
+
+// Middleware wraps next, treating responses matched by isFailure (by
+// default, status >= 500) as failures that count against the caller's
+// failure budget. Once the budget is exhausted, requests for that key are
+// rejected with 429 before reaching next.
+func (frl *FailureRateLimiter) Middleware(next http.Handler, extractor func(*http.Request) string, isFailure func(status int) bool) http.Handler {
+	if isFailure == nil {
+		isFailure = func(status int) bool { return status >= http.StatusInternalServerError }
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := extractor(r)
+
+		reservation, ok := frl.Allow(key)
+		if !ok {
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, http.StatusText(http.StatusTooManyRequests))
+			return
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		if isFailure(rec.status) {
+			reservation.Failed()
+		} else {
+			reservation.Succeeded()
+		}
+	})
+}
+
+// statusRecorder captures the status code written by the wrapped handler
+// so the middleware can classify the response after the fact.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Allow 5 failures per client IP, refilling at 5 per minute, before
+	// rejecting further attempts; idle clients are evicted after an hour.
+	frl := NewFailureRateLimiter(ctx, rate.Every(time.Minute/5), 5, time.Hour)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, http.StatusText(http.StatusOK))
+	})
+
+	extractor := func(r *http.Request) string { return r.RemoteAddr }
+
+	http.Handle("/", frl.Middleware(next, extractor, nil))
+
+	log.Fatal(http.ListenAndServe(":8080", nil))
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestFailureRateLimiter(t *testing.T) {
+	testKey := "test-key"
+
+	t.Run("successful operations do not consume the failure budget", func(t *testing.T) {
+		frl := NewFailureRateLimiter(context.Background(), rate.Every(time.Hour), 1, time.Hour)
+
+		for i := 0; i < 100; i++ {
+			reservation, ok := frl.Allow(testKey)
+			assert.True(t, ok)
+			reservation.Succeeded()
+		}
+	})
+
+	t.Run("failures exhaust the budget and block further attempts", func(t *testing.T) {
+		frl := NewFailureRateLimiter(context.Background(), rate.Every(time.Hour), 1, time.Hour)
+
+		reservation, ok := frl.Allow(testKey)
+		assert.True(t, ok)
+		reservation.Failed()
+
+		_, ok = frl.Allow(testKey)
+		assert.False(t, ok)
+	})
+
+	t.Run("different keys have independent budgets", func(t *testing.T) {
+		frl := NewFailureRateLimiter(context.Background(), rate.Every(time.Hour), 1, time.Hour)
+
+		reservation, ok := frl.Allow("key-1")
+		assert.True(t, ok)
+		reservation.Failed()
+
+		_, ok = frl.Allow("key-1")
+		assert.False(t, ok)
+
+		_, ok = frl.Allow("key-2")
+		assert.True(t, ok)
+	})
+}
@@ -5,67 +5,34 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"sync/atomic"
 	"time"
-)
-
-type LoadShedder struct {
-	isOverloaded atomic.Bool
-}
-
-func NewLoadShedder(ctx context.Context, checkInterval, overloadFactor time.Duration) *LoadShedder {
-	ls := LoadShedder{}
-
-	go ls.runOverloadDetector(ctx, checkInterval, overloadFactor)
-
-	return &ls
-}
 
-func (ls *LoadShedder) runOverloadDetector(ctx context.Context, checkInterval, overloadFactor time.Duration) {
-	ticker := time.NewTicker(checkInterval)
-	defer ticker.Stop()
-
-	// Start with a fresh start time.
-	startTime := time.Now()
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			// Check how long it took to process the last batch of requests.
-			elapsed := time.Since(startTime)
-			if elapsed > overloadFactor {
-				// If it took longer than the overload factor, we're overloaded.
-				ls.isOverloaded.Store(true)
-			} else {
-				// Otherwise, we're not overloaded.
-				ls.isOverloaded.Store(false)
-			}
-			// Reset the start time.
-			startTime = time.Now()
-		}
-	}
-}
-
-func (ls *LoadShedder) IsOverloaded() bool {
-	return ls.isOverloaded.Load()
-}
+	"github.com/ivanlemeshev/resilience"
+)
 
+// Handler demonstrates wiring a resilience.LoadShedder into an HTTP
+// handler, recording each request's service time so the shedder's
+// controller can react to observed latency.
 type Handler struct {
-	ls *LoadShedder
+	ls *resilience.LoadShedder
 }
 
-func NewHandler(ls *LoadShedder) *Handler {
+func NewHandler(ls *resilience.LoadShedder) *Handler {
 	return &Handler{ls: ls}
 }
 
 func (h *Handler) Handler(w http.ResponseWriter, r *http.Request) {
-	if h.ls.IsOverloaded() {
+	if h.ls.ShouldShed() {
 		w.WriteHeader(http.StatusServiceUnavailable)
 		fmt.Fprint(w, http.StatusText(http.StatusServiceUnavailable))
 		return
 	}
 
+	start := time.Now()
+	defer func() {
+		h.ls.Record(time.Since(start))
+	}()
+
 	w.WriteHeader(http.StatusOK)
 	fmt.Fprint(w, http.StatusText(http.StatusOK))
 }
@@ -74,9 +41,9 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// The load shedder will check every 100ms if the last batch of requests
-	// took longer than 200ms.
-	ls := NewLoadShedder(ctx, 100*time.Millisecond, 200*time.Millisecond)
+	// Start shedding once the minimum observed latency exceeds 5ms for
+	// longer than a 100ms interval.
+	ls := resilience.NewLoadShedder(ctx, 5*time.Millisecond, 100*time.Millisecond)
 
 	h := NewHandler(ls)
 	http.HandleFunc("/", h.Handler)
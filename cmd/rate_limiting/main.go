@@ -0,0 +1,222 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Result describes the outcome of a rate limit check for a single key. It
+// carries enough information for a handler to emit the usual
+// X-RateLimit-* and Retry-After headers.
+type Result struct {
+	// Allowed reports whether the request should proceed.
+	Allowed bool
+	// Limit is how many requests a fresh key may make back-to-back: the
+	// configured burst plus one.
+	Limit int
+	// Remaining is the number of requests still allowed in the current
+	// burst window.
+	Remaining int
+	// Reset is how long until the limiter returns to full capacity.
+	Reset time.Duration
+	// RetryAfter is how long the caller should wait before retrying. It is
+	// only meaningful when Allowed is false.
+	RetryAfter time.Duration
+}
+
+// Store persists the theoretical arrival time (TAT) the GCRA algorithm
+// tracks for each key. Implementations must be safe for concurrent use.
+type Store interface {
+	// Load returns the stored TAT for key and whether one was found.
+	Load(key string) (tat time.Time, ok bool)
+	// Save records the TAT for key.
+	Save(key string, tat time.Time)
+}
+
+// RateLimiter checks whether a request identified by key should be allowed.
+type RateLimiter interface {
+	Allow(key string) Result
+}
+
+// gcraLimiter implements RateLimiter using the Generic Cell Rate Algorithm.
+// Unlike a fixed window counter, GCRA allows a configurable burst on top of
+// a sustained rate without the edge effects of wall-clock windows.
+type gcraLimiter struct {
+	store Store
+
+	rate   int
+	period time.Duration
+	burst  int
+
+	emissionInterval time.Duration
+	delayVariation   time.Duration
+
+	now func() time.Time
+}
+
+// NewRateLimiter returns a RateLimiter that allows up to rate requests per
+// period per key, with burst extra requests permitted on top of the
+// sustained rate.
+func NewRateLimiter(rate int, period time.Duration, burst int, store Store) RateLimiter {
+	emissionInterval := period / time.Duration(rate)
+
+	return &gcraLimiter{
+		store:            store,
+		rate:             rate,
+		period:           period,
+		burst:            burst,
+		emissionInterval: emissionInterval,
+		delayVariation:   emissionInterval * time.Duration(burst+1),
+		now:              time.Now,
+	}
+}
+
+func (l *gcraLimiter) Allow(key string) Result {
+	now := l.now()
+
+	tat, ok := l.store.Load(key)
+	if !ok || tat.Before(now) {
+		tat = now
+	}
+
+	newTAT := tat.Add(l.emissionInterval)
+	allowAt := newTAT.Add(-l.delayVariation)
+
+	if now.Before(allowAt) {
+		return Result{
+			Allowed:    false,
+			Limit:      l.burst + 1,
+			Remaining:  0,
+			Reset:      tat.Sub(now),
+			RetryAfter: allowAt.Sub(now),
+		}
+	}
+
+	l.store.Save(key, newTAT)
+
+	// Remaining is how many more requests could be admitted right now
+	// before the next one would be throttled.
+	remaining := int(l.delayVariation-newTAT.Sub(now)) / int(l.emissionInterval)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return Result{
+		Allowed:    true,
+		Limit:      l.burst + 1,
+		Remaining:  remaining,
+		Reset:      newTAT.Sub(now),
+		RetryAfter: 0,
+	}
+}
+
+// memoryEntry is the value held in memoryStore's linked list, pairing a key
+// (so the list element can be removed from the map on eviction) with its
+// TAT.
+type memoryEntry struct {
+	key string
+	tat time.Time
+}
+
+// memoryStore is an in-memory Store backed by a map plus a doubly-linked
+// list tracking recency of use: every Load or Save moves the key's element
+// to the front, and once maxSize is exceeded the least recently used
+// element (the list's back) is evicted. It is the default Store; a Redis
+// or Memcached backed Store can implement the same interface for sharing
+// limits across instances.
+type memoryStore struct {
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+	maxSize int
+}
+
+// NewMemoryStore returns a Store that keeps at most maxSize keys, evicting
+// the least recently used one once that capacity is exceeded.
+func NewMemoryStore(maxSize int) Store {
+	return &memoryStore{
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+		maxSize: maxSize,
+	}
+}
+
+func (s *memoryStore) Load(key string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.entries[key]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	s.order.MoveToFront(elem)
+	return elem.Value.(*memoryEntry).tat, true
+}
+
+func (s *memoryStore) Save(key string, tat time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.entries[key]; ok {
+		elem.Value.(*memoryEntry).tat = tat
+		s.order.MoveToFront(elem)
+		return
+	}
+
+	if len(s.entries) >= s.maxSize {
+		oldest := s.order.Back()
+		if oldest != nil {
+			s.order.Remove(oldest)
+			delete(s.entries, oldest.Value.(*memoryEntry).key)
+		}
+	}
+
+	s.entries[key] = s.order.PushFront(&memoryEntry{key: key, tat: tat})
+}
+
+// Handler demonstrates wiring RateLimiter into an HTTP handler, extracting
+// the rate limit key from a request header and surfacing the standard
+// rate limit headers.
+type Handler struct {
+	rl RateLimiter
+}
+
+func NewHandler(rl RateLimiter) *Handler {
+	return &Handler{rl: rl}
+}
+
+func (h *Handler) Handler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+
+	result := h.rl.Allow(userID)
+
+	w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", result.Limit))
+	w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", result.Remaining))
+	w.Header().Set("X-RateLimit-Reset", fmt.Sprintf("%d", int(result.Reset.Seconds())))
+
+	if !result.Allowed {
+		w.Header().Set("Retry-After", fmt.Sprintf("%d", int(result.RetryAfter.Seconds())+1))
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, http.StatusText(http.StatusTooManyRequests))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, http.StatusText(http.StatusOK))
+}
+
+func main() {
+	// Allow 10 requests per 100 milliseconds per user, with a burst of 5
+	// on top of the sustained rate.
+	rl := NewRateLimiter(10, 100*time.Millisecond, 5, NewMemoryStore(65536))
+
+	h := NewHandler(rl)
+	http.HandleFunc("/", h.Handler)
+
+	log.Fatal(http.ListenAndServe(":8080", nil))
+}
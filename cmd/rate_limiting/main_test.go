@@ -13,45 +13,74 @@ func TestRateLimiting(t *testing.T) {
 	testUserID2 := "test-user-id-2"
 
 	t.Run("request rate is lower than the capacity", func(t *testing.T) {
-		// We allow 10 requests per 100 milliseconds per user.
-		rl := NewRateLimiter(10, 100*time.Millisecond)
+		// We allow 10 requests per 100 milliseconds per user, no burst, so
+		// the emission interval is 10ms; spacing requests well above that
+		// always leaves room for the next one.
+		rl := NewRateLimiter(10, 100*time.Millisecond, 0, NewMemoryStore(65536))
 		for i := 0; i < 100; i++ {
-			assert.False(t, rl.IsLimitReached(testUserID1))
+			assert.True(t, rl.Allow(testUserID1).Allowed)
 			time.Sleep(20 * time.Millisecond)
 		}
 	})
 
 	t.Run("request rate is equal to the capacity", func(t *testing.T) {
-		// We allow 10 requests per 100 milliseconds per user.
-		rl := NewRateLimiter(10, 100*time.Millisecond)
+		// Spacing requests at roughly the emission interval (10ms), with
+		// a little slack for scheduling jitter, should still be allowed.
+		rl := NewRateLimiter(10, 100*time.Millisecond, 0, NewMemoryStore(65536))
 		for i := 0; i < 100; i++ {
-			assert.False(t, rl.IsLimitReached(testUserID1))
-			time.Sleep(10 * time.Millisecond)
+			assert.True(t, rl.Allow(testUserID1).Allowed)
+			time.Sleep(12 * time.Millisecond)
 		}
 	})
 
 	t.Run("request rate is higher than the capacity", func(t *testing.T) {
-		// We allow 10 requests per 100 milliseconds per user.
-		rl := NewRateLimiter(10, 100*time.Millisecond)
-		for i := 0; i < 10; i++ {
-			assert.False(t, rl.IsLimitReached(testUserID1))
-			time.Sleep(5 * time.Millisecond)
+		// No burst, so the emission interval (10ms) is the only slack
+		// available: a request immediately followed by more requests is
+		// throttled until the interval has elapsed again.
+		rl := NewRateLimiter(10, 100*time.Millisecond, 0, NewMemoryStore(65536))
+		for i := 0; i < 5; i++ {
+			assert.True(t, rl.Allow(testUserID1).Allowed)
+
+			for j := 0; j < 3; j++ {
+				assert.False(t, rl.Allow(testUserID1).Allowed)
+			}
+
+			time.Sleep(15 * time.Millisecond)
 		}
+	})
 
-		for i := 0; i < 10; i++ {
-			assert.True(t, rl.IsLimitReached(testUserID1))
-			time.Sleep(5 * time.Millisecond)
+	t.Run("burst allows a short burst above the sustained rate", func(t *testing.T) {
+		// A burst of 4 on top of the sustained rate allows 5 requests to
+		// go through back-to-back before the limiter starts rejecting.
+		rl := NewRateLimiter(10, 100*time.Millisecond, 4, NewMemoryStore(65536))
+
+		for i := 0; i < 5; i++ {
+			assert.True(t, rl.Allow(testUserID1).Allowed)
 		}
+		assert.False(t, rl.Allow(testUserID1).Allowed)
+
+		time.Sleep(100 * time.Millisecond)
+		assert.True(t, rl.Allow(testUserID1).Allowed)
+	})
+
+	t.Run("rejected requests carry a retry-after", func(t *testing.T) {
+		// A burst of 9 allows 10 requests through immediately; the 11th
+		// is rejected and must report how long to wait before retrying.
+		rl := NewRateLimiter(10, 100*time.Millisecond, 9, NewMemoryStore(65536))
 
 		for i := 0; i < 10; i++ {
-			assert.False(t, rl.IsLimitReached(testUserID1))
-			time.Sleep(5 * time.Millisecond)
+			assert.True(t, rl.Allow(testUserID1).Allowed)
 		}
+
+		result := rl.Allow(testUserID1)
+		assert.False(t, result.Allowed)
+		assert.Greater(t, result.RetryAfter, time.Duration(0))
 	})
 
 	t.Run("requests from several users", func(t *testing.T) {
-		// We allow 10 requests per 100 milliseconds per user.
-		rl := NewRateLimiter(10, 100*time.Millisecond)
+		// We allow 10 requests per 100 milliseconds per user, no burst.
+		store := NewMemoryStore(65536)
+		rl := NewRateLimiter(10, 100*time.Millisecond, 0, store)
 
 		wg := sync.WaitGroup{}
 
@@ -59,19 +88,9 @@ func TestRateLimiting(t *testing.T) {
 		go func() {
 			defer wg.Done()
 
+			assert.True(t, rl.Allow(testUserID1).Allowed)
 			for i := 0; i < 10; i++ {
-				assert.False(t, rl.IsLimitReached(testUserID1))
-				time.Sleep(5 * time.Millisecond)
-			}
-
-			for i := 0; i < 10; i++ {
-				assert.True(t, rl.IsLimitReached(testUserID1))
-				time.Sleep(5 * time.Millisecond)
-			}
-
-			for i := 0; i < 10; i++ {
-				assert.False(t, rl.IsLimitReached(testUserID1))
-				time.Sleep(5 * time.Millisecond)
+				assert.False(t, rl.Allow(testUserID1).Allowed)
 			}
 		}()
 
@@ -79,12 +98,36 @@ func TestRateLimiting(t *testing.T) {
 		go func() {
 			defer wg.Done()
 
-			for i := 0; i < 100; i++ {
-				assert.False(t, rl.IsLimitReached(testUserID2))
-				time.Sleep(10 * time.Millisecond)
+			for i := 0; i < 20; i++ {
+				assert.True(t, rl.Allow(testUserID2).Allowed)
+				time.Sleep(15 * time.Millisecond)
 			}
 		}()
 
 		wg.Wait()
 	})
 }
+
+func TestMemoryStoreEviction(t *testing.T) {
+	store := NewMemoryStore(2)
+	now := time.Now()
+
+	store.Save("a", now)
+	store.Save("b", now)
+
+	// Touch "a" so it becomes the most recently used, leaving "b" as the
+	// least recently used entry.
+	_, ok := store.Load("a")
+	assert.True(t, ok)
+
+	store.Save("c", now) // exceeds capacity: evicts the least recently used key
+
+	_, ok = store.Load("a")
+	assert.True(t, ok, "a was touched most recently and should survive eviction")
+
+	_, ok = store.Load("b")
+	assert.False(t, ok, "b was the least recently used key and should have been evicted")
+
+	_, ok = store.Load("c")
+	assert.True(t, ok)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ivanlemeshev/resilience"
+	"golang.org/x/time/rate"
+)
+
+// SourceExtractor extracts the key a request should be rate limited by,
+// e.g. the client IP, an API key header, or an authenticated user ID.
+type SourceExtractor func(*http.Request) (string, error)
+
+// SourceIP extracts the request's remote address as the rate limit key.
+func SourceIP(r *http.Request) (string, error) {
+	return r.RemoteAddr, nil
+}
+
+type sourceEntry struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+// sourceStore is a bounded, TTL-evicted map of per-source token buckets, so
+// idle sources don't accumulate forever.
+type sourceStore struct {
+	mu      sync.Mutex
+	entries map[string]*sourceEntry
+	maxSize int
+	ttl     time.Duration
+
+	rate  rate.Limit
+	burst int
+}
+
+func newSourceStore(ctx context.Context, maxSize int, ttl time.Duration, r rate.Limit, burst int) *sourceStore {
+	s := &sourceStore{
+		entries: make(map[string]*sourceEntry),
+		maxSize: maxSize,
+		ttl:     ttl,
+		rate:    r,
+		burst:   burst,
+	}
+
+	go s.runEvictor(ctx)
+
+	return s
+}
+
+func (s *sourceStore) runEvictor(ctx context.Context) {
+	ticker := time.NewTicker(s.ttl)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			now := time.Now()
+			for key, e := range s.entries {
+				if now.Sub(e.lastUsed) > s.ttl {
+					delete(s.entries, key)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *sourceStore) limiterFor(key string) *rate.Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if ok {
+		e.lastUsed = time.Now()
+		return e.limiter
+	}
+
+	if len(s.entries) >= s.maxSize {
+		// Evict an arbitrary entry to make room for the new source.
+		for k := range s.entries {
+			delete(s.entries, k)
+			break
+		}
+	}
+
+	e = &sourceEntry{
+		limiter:  rate.NewLimiter(s.rate, s.burst),
+		lastUsed: time.Now(),
+	}
+	s.entries[key] = e
+
+	return e.limiter
+}
+
+// SourceLimiter shapes traffic with one token bucket per extracted source.
+// Unlike LoadShedder, which reacts to overall latency, SourceLimiter
+// enforces a fixed rate per caller and smooths bursts by delaying requests
+// rather than always rejecting them outright.
+type SourceLimiter struct {
+	extractor SourceExtractor
+	store     *sourceStore
+	maxDelay  time.Duration
+}
+
+// NewSourceLimiter returns a SourceLimiter allowing r requests per second
+// per source (with a bucket of burst tokens), extracting the source with
+// extractor. Sources idle for longer than ttl are evicted from the
+// bounded store of at most maxSources entries. A reservation whose delay
+// would exceed maxDelay is rejected immediately instead of being shaped.
+// The background eviction goroutine stops once ctx is done.
+func NewSourceLimiter(ctx context.Context, extractor SourceExtractor, r rate.Limit, burst, maxSources int, ttl, maxDelay time.Duration) *SourceLimiter {
+	return &SourceLimiter{
+		extractor: extractor,
+		store:     newSourceStore(ctx, maxSources, ttl, r, burst),
+		maxDelay:  maxDelay,
+	}
+}
+
+// Middleware wraps next with the source limiter. If overloaded is non-nil
+// and reports true, the request is rejected immediately without consuming
+// a token: the load shedder wins when the service is overloaded, and the
+// source limiter only shapes traffic the rest of the time.
+func (sl *SourceLimiter) Middleware(next http.Handler, overloaded func() bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if overloaded != nil && overloaded() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, http.StatusText(http.StatusServiceUnavailable))
+			return
+		}
+
+		key, err := sl.extractor(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, http.StatusText(http.StatusBadRequest))
+			return
+		}
+
+		limiter := sl.store.limiterFor(key)
+
+		reservation := limiter.Reserve()
+		if !reservation.OK() {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, http.StatusText(http.StatusInternalServerError))
+			return
+		}
+
+		delay := reservation.Delay()
+		if delay > sl.maxDelay {
+			reservation.Cancel()
+			w.Header().Set("Retry-After", fmt.Sprintf("%d", int(delay.Seconds())+1))
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, http.StatusText(http.StatusTooManyRequests))
+			return
+		}
+
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+type Handler struct{}
+
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+func (h *Handler) Handler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, http.StatusText(http.StatusOK))
+}
+
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Allow 10 requests per second per source, with a burst of 5, shaping
+	// delays of up to 200ms and rejecting anything that would wait longer.
+	// Sources idle for more than 10 minutes are evicted from the bounded
+	// 65536-entry store.
+	sl := NewSourceLimiter(ctx, SourceIP, 10, 5, 65536, 10*time.Minute, 200*time.Millisecond)
+
+	// The CoDel-style LoadShedder is the coarse, latency-driven circuit
+	// breaker and wins outright when the service is overloaded; the
+	// source limiter only shapes traffic the rest of the time.
+	ls := resilience.NewLoadShedder(ctx, 5*time.Millisecond, 100*time.Millisecond)
+
+	h := NewHandler()
+
+	// Record each request's service time so the shedder can detect
+	// overload.
+	recorded := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		h.Handler(w, r)
+		ls.Record(time.Since(start))
+	})
+	http.Handle("/", sl.Middleware(recorded, ls.ShouldShed))
+
+	log.Fatal(http.ListenAndServe(":8080", nil))
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func doRequest(t *testing.T, handler http.Handler, remoteAddr string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestSourceLimiter(t *testing.T) {
+	t.Run("requests within the burst pass through immediately", func(t *testing.T) {
+		sl := NewSourceLimiter(context.Background(), SourceIP, 10, 5, 65536, time.Hour, 50*time.Millisecond)
+		handler := sl.Middleware(okHandler(), nil)
+
+		for i := 0; i < 5; i++ {
+			rec := doRequest(t, handler, "1.2.3.4:1111")
+			assert.Equal(t, http.StatusOK, rec.Code)
+		}
+	})
+
+	t.Run("an extractor error is rejected with 400", func(t *testing.T) {
+		extractor := func(r *http.Request) (string, error) { return "", errors.New("no source") }
+		sl := NewSourceLimiter(context.Background(), extractor, 10, 5, 65536, time.Hour, 50*time.Millisecond)
+		handler := sl.Middleware(okHandler(), nil)
+
+		rec := doRequest(t, handler, "1.2.3.4:1111")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("a delay beyond maxDelay is rejected with 429 and a retry-after", func(t *testing.T) {
+		// One token per second with a burst of 1: the first request
+		// consumes the only token immediately, and the second back-to-back
+		// request must wait ~1s for the next one, far beyond a 10ms
+		// maxDelay.
+		sl := NewSourceLimiter(context.Background(), SourceIP, 1, 1, 65536, time.Hour, 10*time.Millisecond)
+		handler := sl.Middleware(okHandler(), nil)
+
+		rec := doRequest(t, handler, "1.2.3.4:1111")
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = doRequest(t, handler, "1.2.3.4:1111")
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+	})
+
+	t.Run("a delay within maxDelay is shaped rather than rejected", func(t *testing.T) {
+		// 100 requests per second with a burst of 1: the first request
+		// consumes the only token immediately, and the second back-to-back
+		// request needs to wait ~10ms, comfortably under a 50ms maxDelay.
+		sl := NewSourceLimiter(context.Background(), SourceIP, 100, 1, 65536, time.Hour, 50*time.Millisecond)
+		handler := sl.Middleware(okHandler(), nil)
+
+		rec := doRequest(t, handler, "1.2.3.4:1111")
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		start := time.Now()
+		rec = doRequest(t, handler, "1.2.3.4:1111")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.GreaterOrEqual(t, time.Since(start), 5*time.Millisecond)
+	})
+
+	t.Run("an overloaded callback rejects with 503 without consuming a token", func(t *testing.T) {
+		// One token per second with a burst of 1: had the rejected request
+		// consumed the only token, the next one would be rejected with 429.
+		sl := NewSourceLimiter(context.Background(), SourceIP, 1, 1, 65536, time.Hour, 10*time.Millisecond)
+		overloaded := func() bool { return true }
+		handler := sl.Middleware(okHandler(), overloaded)
+
+		rec := doRequest(t, handler, "1.2.3.4:1111")
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+		// Since the overloaded check short-circuits before the limiter is
+		// consulted, a request from the same source succeeds once the
+		// service is no longer overloaded.
+		rec = doRequest(t, sl.Middleware(okHandler(), nil), "1.2.3.4:1111")
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("different sources have independent budgets", func(t *testing.T) {
+		sl := NewSourceLimiter(context.Background(), SourceIP, 1, 1, 65536, time.Hour, 10*time.Millisecond)
+		handler := sl.Middleware(okHandler(), nil)
+
+		rec := doRequest(t, handler, "1.2.3.4:1111")
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = doRequest(t, handler, "1.2.3.4:1111")
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+		rec = doRequest(t, handler, "5.6.7.8:2222")
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
@@ -0,0 +1,185 @@
+package resilience
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencySample is a single observed request service time.
+type latencySample struct {
+	at       time.Time
+	duration time.Duration
+}
+
+// Stats is a snapshot of the load shedder's observed latency percentiles
+// and current drop probability, useful for tuning target/interval.
+type Stats struct {
+	P50             time.Duration
+	P95             time.Duration
+	P99             time.Duration
+	DropProbability float64
+}
+
+// LoadShedder is a CoDel-inspired proportional controller: it tracks a
+// rolling window of request service times and, whenever the minimum
+// latency observed in that window stays above target for longer than
+// interval, starts dropping a fraction of requests. The drop probability
+// doubles on each interval the overload persists and halves once the
+// minimum latency falls back under target, which avoids the thundering
+// herd on/off oscillation a tick-based boolean shedder produces.
+type LoadShedder struct {
+	target   time.Duration
+	interval time.Duration
+
+	mu              sync.Mutex
+	samples         []latencySample
+	dropProbability float64
+	overloadSince   time.Time
+
+	rng *rand.Rand
+}
+
+// NewLoadShedder returns a LoadShedder that starts shedding load once the
+// minimum request latency exceeds target for longer than interval. The
+// background controller goroutine stops once ctx is done.
+func NewLoadShedder(ctx context.Context, target, interval time.Duration) *LoadShedder {
+	ls := &LoadShedder{
+		target:   target,
+		interval: interval,
+		rng:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	go ls.runController(ctx)
+
+	return ls
+}
+
+func (ls *LoadShedder) runController(ctx context.Context) {
+	ticker := time.NewTicker(ls.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ls.evaluate()
+		}
+	}
+}
+
+// evaluate inspects the samples collected since the last tick, adjusts the
+// drop probability accordingly, and resets the window.
+func (ls *LoadShedder) evaluate() {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	minLatency, ok := minDuration(ls.samples)
+	ls.samples = ls.samples[:0]
+
+	if !ok {
+		return
+	}
+
+	if minLatency > ls.target {
+		if ls.overloadSince.IsZero() {
+			ls.overloadSince = time.Now()
+			return
+		}
+
+		if time.Since(ls.overloadSince) > ls.interval {
+			if ls.dropProbability == 0 {
+				ls.dropProbability = 0.01
+			} else {
+				ls.dropProbability = minFloat(ls.dropProbability*2, 1)
+			}
+			ls.overloadSince = time.Now()
+		}
+		return
+	}
+
+	ls.overloadSince = time.Time{}
+	if ls.dropProbability > 0 {
+		ls.dropProbability /= 2
+		if ls.dropProbability < 0.001 {
+			ls.dropProbability = 0
+		}
+	}
+}
+
+// Record reports the service time of a completed request.
+func (ls *LoadShedder) Record(d time.Duration) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	ls.samples = append(ls.samples, latencySample{at: time.Now(), duration: d})
+}
+
+// ShouldShed reports whether the next request should be dropped, drawn
+// proportionally to the current drop probability.
+func (ls *LoadShedder) ShouldShed() bool {
+	ls.mu.Lock()
+	p := ls.dropProbability
+	ls.mu.Unlock()
+
+	if p <= 0 {
+		return false
+	}
+
+	return ls.rng.Float64() < p
+}
+
+// Stats returns the currently observed latency percentiles and drop
+// probability.
+func (ls *LoadShedder) Stats() Stats {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	durations := make([]time.Duration, len(ls.samples))
+	for i, s := range ls.samples {
+		durations[i] = s.duration
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	return Stats{
+		P50:             percentile(durations, 0.50),
+		P95:             percentile(durations, 0.95),
+		P99:             percentile(durations, 0.99),
+		DropProbability: ls.dropProbability,
+	}
+}
+
+func minDuration(samples []latencySample) (time.Duration, bool) {
+	if len(samples) == 0 {
+		return 0, false
+	}
+
+	min := samples[0].duration
+	for _, s := range samples[1:] {
+		if s.duration < min {
+			min = s.duration
+		}
+	}
+	return min, true
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// percentile returns the p-th percentile (0 <= p <= 1) of a sorted slice
+// of durations, or 0 if the slice is empty.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	idx := int(p * float64(len(sorted)-1))
+	return sorted[idx]
+}